@@ -0,0 +1,27 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+//RateLimitedError is returned when a request exhausts its retry budget while
+//still being rate limited by Slack (HTTP 429)
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (err RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by Slack, retry after %s", err.RetryAfter)
+}
+
+//StatusCodeError is returned for non-retryable 4xx/5xx responses
+type StatusCodeError struct {
+	StatusCode int
+	Endpoint   string
+	Body       []byte
+}
+
+func (err StatusCodeError) Error() string {
+	return fmt.Sprintf("bad status code %d received from %s", err.StatusCode, err.Endpoint)
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileStreamsMultipartFields(t *testing.T) {
+	var (
+		gotContentType string
+		fields         = map[string]string{}
+		fileContent    []byte
+		fileName       string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+
+		_, params, err := mime.ParseMediaType(gotContentType)
+		if err != nil {
+			t.Fatalf("failed to parse Content-Type: %v", err)
+		}
+
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+
+			data, err := ioutil.ReadAll(part)
+			if err != nil {
+				t.Fatalf("failed to read part %q: %v", part.FormName(), err)
+			}
+
+			if part.FileName() != "" {
+				fileName = part.FileName()
+				fileContent = data
+				continue
+			}
+
+			fields[part.FormName()] = string(data)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"file":{"id":"F1","name":"result.zip"}}`))
+	}))
+	defer server.Close()
+
+	slackClient := SlackClient{Client: server.Client(), BaseURL: server.URL, OAuthToken: "xoxb-test"}
+
+	content := strings.Repeat("payload-bytes ", 1000)
+	file, err := slackClient.UploadFile(context.Background(), FileUploadParameters{
+		Channels:       []string{"C1", "C2"},
+		Filename:       "result.zip",
+		Title:          "Themed result",
+		InitialComment: "here you go",
+		ThreadTS:       "123.456",
+	}, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if file.ID != "F1" || file.Name != "result.zip" {
+		t.Fatalf("unexpected decoded file: %+v", file)
+	}
+
+	if !strings.HasPrefix(gotContentType, "multipart/form-data") {
+		t.Fatalf("expected a multipart/form-data Content-Type, got %q", gotContentType)
+	}
+
+	if fields["channels"] != "C1,C2" {
+		t.Fatalf("expected channels field %q, got %q", "C1,C2", fields["channels"])
+	}
+	if fields["title"] != "Themed result" {
+		t.Fatalf("expected title field, got %q", fields["title"])
+	}
+	if fields["initial_comment"] != "here you go" {
+		t.Fatalf("expected initial_comment field, got %q", fields["initial_comment"])
+	}
+	if fields["thread_ts"] != "123.456" {
+		t.Fatalf("expected thread_ts field, got %q", fields["thread_ts"])
+	}
+
+	if fileName != "result.zip" {
+		t.Fatalf("expected the uploaded part's filename to be %q, got %q", "result.zip", fileName)
+	}
+	if string(fileContent) != content {
+		t.Fatalf("uploaded file content was not streamed through correctly")
+	}
+}
+
+func TestUploadFileSurfacesSlackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+	}))
+	defer server.Close()
+
+	slackClient := SlackClient{Client: server.Client(), BaseURL: server.URL}
+
+	_, err := slackClient.UploadFile(context.Background(), FileUploadParameters{Filename: "x.zip"}, strings.NewReader("x"))
+	if err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Fatalf("expected the Slack error to surface, got %v", err)
+	}
+}
+
+func TestDownloadFileStreamsBodyIntoWriter(t *testing.T) {
+	want := strings.Repeat("downloaded-bytes ", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(want))
+	}))
+	defer server.Close()
+
+	slackClient := SlackClient{Client: server.Client(), OAuthToken: "xoxb-test"}
+
+	var buf bytes.Buffer
+	if err := slackClient.DownloadFile(context.Background(), server.URL, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.String() != want {
+		t.Fatalf("downloaded content did not match")
+	}
+}
+
+func TestDownloadFileReturnsStatusCodeErrorOnFailureResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer server.Close()
+
+	slackClient := SlackClient{Client: server.Client()}
+
+	var buf bytes.Buffer
+	err := slackClient.DownloadFile(context.Background(), server.URL, &buf)
+
+	statusErr, ok := err.(StatusCodeError)
+	if !ok {
+		t.Fatalf("expected a StatusCodeError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", statusErr.StatusCode)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing to be written to w on a failure response, got %q", buf.String())
+	}
+}
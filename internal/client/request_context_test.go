@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//fakeMetrics records the latest ObserveRequest call so tests can assert on attempt counts
+type fakeMetrics struct {
+	endpoint string
+	attempts int
+	waited   time.Duration
+	err      error
+}
+
+func (m *fakeMetrics) ObserveRequest(endpoint string, attempts int, waited time.Duration, err error) {
+	m.endpoint = endpoint
+	m.attempts = attempts
+	m.waited = waited
+	m.err = err
+}
+
+func TestRequestContextRetriesThenSucceeds(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	metrics := &fakeMetrics{}
+	slackClient := SlackClient{
+		Client:      server.Client(),
+		BaseURL:     server.URL,
+		RetryConfig: RetryConfig{MaxAttempts: 3, MaxWait: time.Second},
+		Metrics:     metrics,
+	}
+
+	body, statusCode, err := slackClient.requestContext(context.Background(), http.MethodGet, "/test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", statusCode)
+	}
+	if string(body) != `{"ok":true}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the 429 to be retried exactly once, got %d requests", requests)
+	}
+	if metrics.attempts != 2 {
+		t.Fatalf("expected Metrics to observe 2 attempts, got %d", metrics.attempts)
+	}
+}
+
+func TestRequestContextGivesUpAfterMaxAttempts(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	metrics := &fakeMetrics{}
+	slackClient := SlackClient{
+		Client:      server.Client(),
+		BaseURL:     server.URL,
+		RetryConfig: RetryConfig{MaxAttempts: 3, MaxWait: time.Second},
+		Metrics:     metrics,
+	}
+
+	_, statusCode, err := slackClient.requestContext(context.Background(), http.MethodGet, "/test", nil)
+	if _, ok := err.(RateLimitedError); !ok {
+		t.Fatalf("expected a RateLimitedError once attempts are exhausted, got %v", err)
+	}
+	if statusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", statusCode)
+	}
+	if requests != 3 {
+		t.Fatalf("expected exactly MaxAttempts=3 requests, got %d", requests)
+	}
+	if metrics.attempts != 3 {
+		t.Fatalf("expected Metrics to observe 3 attempts, got %d", metrics.attempts)
+	}
+}
+
+func TestRequestContextStopsImmediatelyOnCancellationMidWait(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	metrics := &fakeMetrics{}
+	slackClient := SlackClient{
+		Client:      server.Client(),
+		BaseURL:     server.URL,
+		//MaxWait is long enough that cancellation, not the timer, ends the wait
+		RetryConfig: RetryConfig{MaxAttempts: 5, MaxWait: time.Minute},
+		Metrics:     metrics,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, _, err := slackClient.requestContext(ctx, http.MethodGet, "/test", nil)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected the retry loop to stop after its first attempt, got %d requests", requests)
+	}
+
+	//This is the exact regression d5dd0c2 fixed: a cancellation mid-wait used
+	//to bump attempt to MaxAttempts and let the for-loop's increment push it
+	//one past that before Metrics ever saw it
+	if metrics.attempts != 1 {
+		t.Fatalf("expected Metrics to observe the true attempt count of 1, got %d", metrics.attempts)
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//RetryConfig controls how the client backs off when Slack responds with HTTP 429
+type RetryConfig struct {
+	MaxAttempts int
+	MaxWait     time.Duration
+}
+
+//DefaultRetryConfig is used by clients which don't set RetryConfig explicitly
+var DefaultRetryConfig = RetryConfig{MaxAttempts: 5, MaxWait: 30 * time.Second}
+
+//Metrics receives timing/count information about every request made through the client
+type Metrics interface {
+	ObserveRequest(endpoint string, attempts int, waited time.Duration, err error)
+}
+
+//parseRetryAfter parses Slack's Retry-After header, which can be either a
+//number of seconds or an HTTP-date
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when), true
+	}
+
+	return 0, false
+}
+
+//withJitter adds up to +/-20% jitter to d so concurrent callers don't all retry in lockstep
+func withJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(d)/5 + 1))
+	if rand.Intn(2) == 0 {
+		return d + jitter
+	}
+
+	return d - jitter
+}
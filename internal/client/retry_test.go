@@ -0,0 +1,60 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Run("seconds", func(t *testing.T) {
+		d, ok := parseRetryAfter("5")
+		if !ok || d != 5*time.Second {
+			t.Fatalf("expected 5s, true, got %s, %v", d, ok)
+		}
+	})
+
+	t.Run("http date", func(t *testing.T) {
+		when := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+		d, ok := parseRetryAfter(when)
+		if !ok {
+			t.Fatalf("expected ok=true for HTTP-date header")
+		}
+		if d <= 0 || d > 10*time.Second {
+			t.Fatalf("expected a duration close to 10s, got %s", d)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if _, ok := parseRetryAfter(""); ok {
+			t.Fatalf("expected ok=false for empty header")
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		if _, ok := parseRetryAfter("not-a-number-or-date"); ok {
+			t.Fatalf("expected ok=false for unparseable header")
+		}
+	})
+}
+
+func TestWithJitter(t *testing.T) {
+	t.Run("non-positive is left alone", func(t *testing.T) {
+		if got := withJitter(0); got != 0 {
+			t.Fatalf("expected 0, got %s", got)
+		}
+	})
+
+	t.Run("stays within +/-20%%", func(t *testing.T) {
+		d := 10 * time.Second
+		lower := d - d/5
+		upper := d + d/5
+
+		for i := 0; i < 100; i++ {
+			got := withJitter(d)
+			if got < lower || got > upper {
+				t.Fatalf("jittered duration %s outside [%s, %s]", got, lower, upper)
+			}
+		}
+	})
+}
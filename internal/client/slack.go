@@ -2,46 +2,290 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/sharovik/devbot/internal/dto"
-	"github.com/sharovik/devbot/internal/log"
-	"golang.org/x/net/websocket"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
-	"sync/atomic"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
 )
 
-//SlackClient client for slack api calls
+//SlackClient client for slack api calls. It wraps the official slack-go/slack
+//SDK and its socketmode package, so devbot connects to Slack as a modern app
+//(Socket Mode + Events API) instead of talking to the legacy RTM websocket
 type SlackClient struct {
-	Client     *http.Client
-	BaseURL    string
-	OAuthToken string
+	Client      *http.Client
+	API         *slack.Client
+	Socket      *socketmode.Client
+	BaseURL     string
+	OAuthToken  string
+	AppToken    string
+	RetryConfig RetryConfig
+	Metrics     Metrics
+}
+
+//EventHandler groups the callbacks the Socket Mode event loop dispatches
+//into. OnMessage and OnFileShared are invoked on their own goroutine, since
+//file processors can run for minutes and must not block the loop reading
+//client.Socket.Events - OnSlashCommand and OnInteraction run inline because
+//Slack expects those acknowledged within seconds
+type EventHandler struct {
+	OnMessage      func(message dto.SlackResponseEventMessage)
+	OnFileShared   func(message dto.SlackResponseEventMessage)
+	OnSlashCommand func(cmd slack.SlashCommand) (interface{}, error)
+	OnInteraction  func(callback slack.InteractionCallback) error
 }
 
 //SlackClientInterface interface for slack client
 type SlackClientInterface interface {
-	//Http methods
-	request(string, string, []byte) ([]byte, int, error)
+	//Http methods, still needed for the few endpoints the SDK doesn't cover. The endpoint is appended to BaseURL,
+	//so absolute file URLs go through DownloadFile instead
+	Request(string, string, []byte) ([]byte, int, error)
 	Post(string, []byte) ([]byte, int, error)
 	Get(string) ([]byte, int, error)
 	Put(string, []byte) ([]byte, int, error)
 
+	//Context variants of the above, honoring Slack's Retry-After header on HTTP 429
+	RequestContext(ctx context.Context, method string, endpoint string, body []byte) ([]byte, int, error)
+	PostContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error)
+	GetContext(ctx context.Context, endpoint string) ([]byte, int, error)
+	PutContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error)
+
 	//Methods for slackAPI endpoints
 	GetConversationsList() (dto.SlackResponseConversationsList, int, error)
+	GetConversationsListContext(ctx context.Context) (dto.SlackResponseConversationsList, int, error)
 	GetUsersList() (dto.SlackResponseUsersList, int, error)
-	SendMessageToWs(*websocket.Conn, dto.SlackRequestEventMessage) error
+	GetUsersListContext(ctx context.Context) (dto.SlackResponseUsersList, int, error)
 
 	//PM messages
 	SendMessage(dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error)
+	SendMessageContext(ctx context.Context, message dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error)
+	AttachFileTo(channel string, filePath string, filename string) (dto.SlackResponseChatPostMessage, int, error)
+
+	//UploadFile streams r straight into a multipart/form-data files.upload request, without buffering it in memory
+	UploadFile(ctx context.Context, params FileUploadParameters, r io.Reader) (dto.File, error)
+	//DownloadFile streams the body found at url into w, without buffering it in memory
+	DownloadFile(ctx context.Context, url string, w io.Writer) error
+
+	//Run starts the Socket Mode event loop and blocks until ctx is cancelled
+	Run(ctx context.Context, handler EventHandler) error
+}
+
+//FileUploadParameters describes a files.upload call
+type FileUploadParameters struct {
+	Channels       []string
+	Filename       string
+	Title          string
+	InitialComment string
+	ThreadTS       string
 }
 
+//New creates a SlackClient backed by the slack-go SDK. oauthToken is the bot
+//token used for Web API calls, appToken is the app-level token (xapp-...)
+//used to open the Socket Mode connection
+func New(httpClient *http.Client, baseURL string, oauthToken string, appToken string) SlackClient {
+	api := slack.New(
+		oauthToken,
+		slack.OptionAppLevelToken(appToken),
+		slack.OptionHTTPClient(httpClient),
+	)
+
+	return SlackClient{
+		Client:     httpClient,
+		API:        api,
+		Socket:     socketmode.New(api),
+		BaseURL:    baseURL,
+		OAuthToken: oauthToken,
+		AppToken:   appToken,
+	}
+}
+
+//Run starts the Socket Mode client and dispatches the events devbot cares
+//about (messages, file-shared events, slash commands and interactive
+//actions) into handler. It blocks until ctx is cancelled or the socket is closed for good
+func (client SlackClient) Run(ctx context.Context, handler EventHandler) error {
+	go client.Socket.RunContext(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case evt, ok := <-client.Socket.Events:
+			if !ok {
+				return nil
+			}
+
+			client.dispatch(evt, handler)
+		}
+	}
+}
+
+func (client SlackClient) dispatch(evt socketmode.Event, handler EventHandler) {
+	switch evt.Type {
+	case socketmode.EventTypeEventsAPI:
+		eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			log.Logger().Warn().Interface("event", evt).Msg("Unexpected EventsAPI payload")
+			return
+		}
+
+		if evt.Request != nil {
+			client.Socket.Ack(*evt.Request)
+		}
+
+		switch innerEvent := eventsAPIEvent.InnerEvent.Data.(type) {
+		case *slackevents.MessageEvent:
+			if handler.OnMessage != nil {
+				message := dto.SlackResponseEventMessage{
+					Channel: innerEvent.Channel,
+					User:    innerEvent.User,
+					Text:    innerEvent.Text,
+					Ts:      innerEvent.TimeStamp,
+				}
+				go handler.OnMessage(message)
+			}
+		case *slackevents.FileSharedEvent:
+			if handler.OnFileShared != nil {
+				//GetFileInfo is a Slack API round-trip, so it runs on its own
+				//goroutine too - doing it inline here would block the loop
+				//reading client.Socket.Events on a slow/hanging files.info call
+				fileID, channelID := innerEvent.FileID, innerEvent.ChannelID
+				go func() {
+					file, _, _, err := client.API.GetFileInfo(fileID, 0, 0)
+					if err != nil {
+						log.Logger().AddError(err).Str("file_id", fileID).Msg("Failed to fetch file info")
+						return
+					}
+
+					handler.OnFileShared(dto.SlackResponseEventMessage{
+						Channel: channelID,
+						Files: []dto.File{
+							{
+								ID:         file.ID,
+								Name:       file.Name,
+								Filetype:   file.Filetype,
+								URLPrivate: file.URLPrivate,
+							},
+						},
+					})
+				}()
+			}
+		}
+	case socketmode.EventTypeSlashCommand:
+		cmd, ok := evt.Data.(slack.SlashCommand)
+		if !ok {
+			log.Logger().Warn().Interface("event", evt).Msg("Unexpected slash-command payload")
+			return
+		}
+
+		if handler.OnSlashCommand == nil {
+			if evt.Request != nil {
+				client.Socket.Ack(*evt.Request)
+			}
+			return
+		}
+
+		response, err := handler.OnSlashCommand(cmd)
+		if err != nil {
+			log.Logger().AddError(err).Interface("command", cmd).Msg("Failed to handle slash command")
+		}
+		if evt.Request != nil {
+			client.Socket.Ack(*evt.Request, response)
+		}
+	case socketmode.EventTypeInteractive:
+		callback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			log.Logger().Warn().Interface("event", evt).Msg("Unexpected interaction payload")
+			return
+		}
+
+		if evt.Request != nil {
+			client.Socket.Ack(*evt.Request)
+		}
+
+		if handler.OnInteraction != nil {
+			if err := handler.OnInteraction(callback); err != nil {
+				log.Logger().AddError(err).Interface("callback", callback).Msg("Failed to handle interactive action")
+			}
+		}
+	}
+}
+
+//request is kept for the handful of internal callers which don't carry a context yet
 func (client SlackClient) request(method string, endpoint string, body []byte) ([]byte, int, error) {
+	return client.requestContext(context.Background(), method, endpoint, body)
+}
 
-	log.Logger().StartMessage("Slack request")
+//requestContext performs a single logical request, transparently retrying on
+//HTTP 429 responses by honoring Slack's Retry-After header, up to RetryConfig.MaxAttempts
+func (client SlackClient) requestContext(ctx context.Context, method string, endpoint string, body []byte) ([]byte, int, error) {
+	retryConfig := client.RetryConfig
+	if retryConfig.MaxAttempts <= 0 {
+		retryConfig = DefaultRetryConfig
+	}
+
+	var (
+		response   []byte
+		statusCode int
+		err        error
+		waited     time.Duration
+		attempt    int
+	)
+
+retryLoop:
+	for attempt = 1; attempt <= retryConfig.MaxAttempts; attempt++ {
+		response, statusCode, _, err = client.doRequest(ctx, method, endpoint, body)
+
+		rateLimited, isRateLimited := err.(RateLimitedError)
+		if !isRateLimited || attempt == retryConfig.MaxAttempts {
+			break
+		}
+
+		wait := withJitter(rateLimited.RetryAfter)
+		if wait > retryConfig.MaxWait {
+			wait = retryConfig.MaxWait
+		}
+
+		log.Logger().Warn().
+			Str("endpoint", endpoint).
+			Int("attempt", attempt).
+			Dur("wait", wait).
+			Msg("Rate limited by Slack, retrying")
+
+		waited += wait
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			break retryLoop
+		case <-timer.C:
+		}
+	}
+
+	if client.Metrics != nil {
+		client.Metrics.ObserveRequest(endpoint, attempt, waited, err)
+	}
 
-	var resp *http.Response
+	return response, statusCode, err
+}
+
+//doRequest performs one HTTP round-trip, classifying 429 responses into
+//RateLimitedError and other 4xx/5xx responses into StatusCodeError
+func (client SlackClient) doRequest(ctx context.Context, method string, endpoint string, body []byte) ([]byte, int, time.Duration, error) {
+	log.Logger().StartMessage("Slack request")
+	defer log.Logger().FinishMessage("Slack request")
 
 	log.Logger().Info().
 		Str("base_url", client.BaseURL).
@@ -49,11 +293,10 @@ func (client SlackClient) request(method string, endpoint string, body []byte) (
 		Str("method", method).
 		Msg("Endpoint call")
 
-	request, err := http.NewRequest(method, client.BaseURL+endpoint, bytes.NewReader(body))
+	request, err := http.NewRequestWithContext(ctx, method, client.BaseURL+endpoint, bytes.NewReader(body))
 	if err != nil {
 		log.Logger().AddError(err).Msg("Error during the request generation")
-		log.Logger().FinishMessage("Slack request")
-		return nil, 0, err
+		return nil, 0, 0, err
 	}
 
 	request.Header.Set("Content-Type", "application/json")
@@ -70,41 +313,47 @@ func (client SlackClient) request(method string, endpoint string, body []byte) (
 			Str("response_error", errMsg).
 			Msg("Error during response body parse")
 
-		log.Logger().FinishMessage("Slack request")
-		return nil, 0, err
+		return nil, 0, 0, err
 	}
 
-	//1. Parse the response body
 	defer resp.Body.Close()
 	byteResp, errorConversion := ioutil.ReadAll(resp.Body)
 	if errorConversion != nil {
 		log.Logger().AddError(errorConversion).
 			Err(errorConversion).
 			Msg("Error during response body parse")
-		log.Logger().FinishMessage("Slack request")
-		return byteResp, 0, errorConversion
+		return byteResp, 0, 0, errorConversion
 	}
 
-	var response []byte
-	if string(byteResp) == "" {
-		response = []byte(`{}`)
-	} else {
-		response = byteResp
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if !ok {
+			retryAfter = time.Second
+		}
+
+		log.Logger().Warn().Str("endpoint", endpoint).Dur("retry_after", retryAfter).Msg("Rate limited by Slack")
+		return byteResp, resp.StatusCode, retryAfter, RateLimitedError{RetryAfter: retryAfter}
 	}
 
-	//5. For status codes, which are equal or more then 400, we should return an error. But we must mark it as a warning, because sometimes bad status code related to the validation
 	if resp.StatusCode >= http.StatusBadRequest {
-		err = fmt.Errorf("Bad status code received: %d ", resp.StatusCode)
 		log.Logger().Warn().Int("status_code", resp.StatusCode).
-			Err(err).
-			Str("response", string(response)).
+			Str("response", string(byteResp)).
 			Msg("Bad status code received")
-		log.Logger().FinishMessage("Slack request")
-		return byteResp, resp.StatusCode, err
+		return byteResp, resp.StatusCode, 0, StatusCodeError{StatusCode: resp.StatusCode, Endpoint: endpoint, Body: byteResp}
 	}
 
-	log.Logger().FinishMessage("Slack request")
-	return byteResp, resp.StatusCode, nil
+	return byteResp, resp.StatusCode, 0, nil
+}
+
+//Request exposes the raw HTTP request method to callers outside this package.
+//endpoint is appended to BaseURL, so absolute file URLs go through DownloadFile instead
+func (client SlackClient) Request(method string, endpoint string, body []byte) ([]byte, int, error) {
+	return client.request(method, endpoint, body)
+}
+
+//RequestContext is the context-aware variant of Request
+func (client SlackClient) RequestContext(ctx context.Context, method string, endpoint string, body []byte) ([]byte, int, error) {
+	return client.requestContext(ctx, method, endpoint, body)
 }
 
 //Post method for POST http requests
@@ -112,57 +361,177 @@ func (client SlackClient) Post(endpoint string, body []byte) ([]byte, int, error
 	return client.request(http.MethodPost, endpoint, body)
 }
 
+//PostContext is the context-aware variant of Post
+func (client SlackClient) PostContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	return client.requestContext(ctx, http.MethodPost, endpoint, body)
+}
+
 //Put method for PUT http requests
 func (client SlackClient) Put(endpoint string, body []byte) ([]byte, int, error) {
 	return client.request(http.MethodPut, endpoint, body)
 }
 
+//PutContext is the context-aware variant of Put
+func (client SlackClient) PutContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	return client.requestContext(ctx, http.MethodPut, endpoint, body)
+}
+
 //Get method for GET http requests
 func (client SlackClient) Get(endpoint string) ([]byte, int, error) {
 	return client.request(http.MethodGet, endpoint, []byte(``))
 }
 
-//SendMessageToWs sends message to selected WebSocket EventsAPI
-func (client SlackClient) SendMessageToWs(ws *websocket.Conn, m dto.SlackRequestEventMessage) error {
-	log.Logger().Debug().Interface("message", m).Msg("Send message to EventsAPI")
-	var counter uint64
-	m.Id = atomic.AddUint64(&counter, 1)
-	return websocket.JSON.Send(ws, m)
+//GetContext is the context-aware variant of Get
+func (client SlackClient) GetContext(ctx context.Context, endpoint string) ([]byte, int, error) {
+	return client.requestContext(ctx, http.MethodGet, endpoint, []byte(``))
 }
 
 //SendMessage method for post message send through simple API request
 func (client SlackClient) SendMessage(message dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error) {
+	return client.SendMessageContext(context.Background(), message)
+}
+
+//SendMessageContext is the context-aware variant of SendMessage. It's a thin
+//wrapper over the vendored slack-go client instead of a hand-rolled chat.postMessage call
+func (client SlackClient) SendMessageContext(ctx context.Context, message dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error) {
 	log.Logger().Debug().Interface("message", message).Msg("Start chat.postMessage")
-	byteStr, err := json.Marshal(message)
+
+	_, _, err := client.API.PostMessageContext(ctx, message.Channel, slack.MsgOptionText(message.Text, false))
 	if err != nil {
+		log.Logger().AddError(err).Interface("message", message).Msg("Failed send message")
 		return dto.SlackResponseChatPostMessage{}, 0, err
 	}
 
-	response, statusCode, err := client.Post("/chat.postMessage", byteStr)
+	log.Logger().Debug().Interface("message", message).Msg("Finish chat.postMessage")
+	return dto.SlackResponseChatPostMessage{Ok: true}, http.StatusOK, nil
+}
+
+//AttachFileTo uploads the file found at filePath to channel, streaming it through UploadFile
+func (client SlackClient) AttachFileTo(channel string, filePath string, filename string) (dto.SlackResponseChatPostMessage, int, error) {
+	log.Logger().Debug().Str("channel", channel).Str("file", filePath).Msg("Start files.upload")
+
+	file, err := os.Open(filePath)
 	if err != nil {
-		log.Logger().AddError(err).
-			RawJSON("response", response).
-			Int("status_code", statusCode).
-			Msg("Failed send message")
-		return dto.SlackResponseChatPostMessage{}, statusCode, err
+		return dto.SlackResponseChatPostMessage{}, 0, err
 	}
+	defer file.Close()
 
-	var dtoResponse dto.SlackResponseChatPostMessage
-	if err := json.Unmarshal(response, &dtoResponse); err != nil {
-		return dto.SlackResponseChatPostMessage{}, statusCode, err
+	if _, err := client.UploadFile(context.Background(), FileUploadParameters{
+		Channels: []string{channel},
+		Filename: filename,
+	}, file); err != nil {
+		log.Logger().AddError(err).Str("channel", channel).Str("file", filePath).Msg("Failed to upload file")
+		return dto.SlackResponseChatPostMessage{}, 0, err
 	}
 
-	if !dtoResponse.Ok {
-		return dtoResponse, statusCode, errors.New(dtoResponse.Error)
+	log.Logger().Debug().Str("channel", channel).Str("file", filePath).Msg("Finish files.upload")
+	return dto.SlackResponseChatPostMessage{Ok: true}, http.StatusOK, nil
+}
+
+//UploadFile posts a multipart/form-data files.upload request, streaming r
+//straight into the multipart body so large uploads never sit fully in memory
+func (client SlackClient) UploadFile(ctx context.Context, params FileUploadParameters, r io.Reader) (dto.File, error) {
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+
+	go func() {
+		defer pipeWriter.Close()
+
+		fields := map[string]string{
+			"filename":        params.Filename,
+			"title":           params.Title,
+			"initial_comment": params.InitialComment,
+			"thread_ts":       params.ThreadTS,
+		}
+		if len(params.Channels) > 0 {
+			fields["channels"] = strings.Join(params.Channels, ",")
+		}
+
+		for name, value := range fields {
+			if value == "" {
+				continue
+			}
+			if err := writer.WriteField(name, value); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+		}
+
+		part, err := writer.CreateFormFile("file", params.Filename)
+		if err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+
+		if _, err := io.Copy(part, r); err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+
+		pipeWriter.CloseWithError(writer.Close())
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, client.BaseURL+"/files.upload", pipeReader)
+	if err != nil {
+		return dto.File{}, err
 	}
 
-	log.Logger().Debug().Interface("message", message).Msg("Finish chat.postMessage")
-	return dtoResponse, statusCode, nil
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.OAuthToken))
+
+	resp, err := client.Client.Do(request)
+	if err != nil {
+		return dto.File{}, err
+	}
+	defer resp.Body.Close()
+
+	var uploadResponse struct {
+		Ok    bool     `json:"ok"`
+		Error string   `json:"error"`
+		File  dto.File `json:"file"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
+		return dto.File{}, err
+	}
+
+	if !uploadResponse.Ok {
+		return dto.File{}, errors.New(uploadResponse.Error)
+	}
+
+	return uploadResponse.File, nil
+}
+
+//DownloadFile streams the body found at url into w, without buffering the whole file in memory
+func (client SlackClient) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.OAuthToken))
+
+	resp, err := client.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return StatusCodeError{StatusCode: resp.StatusCode, Endpoint: url, Body: body}
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
 }
 
 //GetConversationsList method which returns the conversations list of current workspace
 func (client SlackClient) GetConversationsList() (dto.SlackResponseConversationsList, int, error) {
-	response, statusCode, err := client.Get("/conversations.list")
+	return client.GetConversationsListContext(context.Background())
+}
+
+//GetConversationsListContext is the context-aware variant of GetConversationsList
+func (client SlackClient) GetConversationsListContext(ctx context.Context) (dto.SlackResponseConversationsList, int, error) {
+	response, statusCode, err := client.GetContext(ctx, "/conversations.list")
 	if err != nil {
 		return dto.SlackResponseConversationsList{}, statusCode, err
 	}
@@ -181,7 +550,12 @@ func (client SlackClient) GetConversationsList() (dto.SlackResponseConversations
 
 //GetUsersList method which returns the users list of current workspace
 func (client SlackClient) GetUsersList() (dto.SlackResponseUsersList, int, error) {
-	response, statusCode, err := client.Get("/users.list")
+	return client.GetUsersListContext(context.Background())
+}
+
+//GetUsersListContext is the context-aware variant of GetUsersList
+func (client SlackClient) GetUsersListContext(ctx context.Context) (dto.SlackResponseUsersList, int, error) {
+	response, statusCode, err := client.GetContext(ctx, "/users.list")
 	if err != nil {
 		return dto.SlackResponseUsersList{}, statusCode, err
 	}
@@ -196,4 +570,4 @@ func (client SlackClient) GetUsersList() (dto.SlackResponseUsersList, int, error
 	}
 
 	return dtoResponse, statusCode, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,137 @@
+//Package config loads devbot's runtime configuration from environment
+//variables, with sane defaults for local development
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+//SlackConfig holds the credentials and endpoint needed to talk to Slack
+type SlackConfig struct {
+	BaseURL    string
+	OAuthToken string
+	AppToken   string
+}
+
+//StorageConfig controls the optional object-storage backend used by
+//UploadProcessor, the /list and /del slash commands, and the background janitor
+type StorageConfig struct {
+	Enabled                bool
+	Dir                    string
+	BaseURL                string
+	MinFreeBytes           int64
+	TTL                    time.Duration
+	JanitorInterval        time.Duration
+	UploadProcessorEnabled bool
+
+	//Backend picks the storage.Backend Bootstrap constructs: "local"
+	//(default, storage.LocalBackend) or "s3" (storage.S3Backend)
+	Backend string
+
+	//S3* configure storage.S3Backend; only read when Backend is "s3"
+	S3Endpoint   string
+	S3Region     string
+	S3Bucket     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3UseSSL     bool
+	S3PublicBase string
+	S3QuotaBytes int64
+
+	//SignedURLsEnabled wraps the chosen backend in storage.HTTPBackend, so
+	//Put returns a signed, expiring URL rather than the backend's raw one
+	SignedURLsEnabled bool
+	SignSecret        string
+	SignURLTTL        time.Duration
+}
+
+//SlackExportConfig controls the optional Slack workspace export importer
+type SlackExportConfig struct {
+	Enabled   bool
+	JSONLPath string
+
+	//Sink picks which slackimport.HistoryStore an export is replayed into:
+	//"jsonl" (default) or "repost"
+	Sink          string
+	TargetChannel string
+}
+
+//Config is devbot's full runtime configuration
+type Config struct {
+	SlackConfig       SlackConfig
+	StorageConfig     StorageConfig
+	SlackExportConfig SlackExportConfig
+}
+
+//Init reads Config from environment variables
+func Init() Config {
+	return Config{
+		SlackConfig: SlackConfig{
+			BaseURL:    getEnv("SLACK_BASE_URL", "https://slack.com/api"),
+			OAuthToken: getEnv("SLACK_OAUTH_TOKEN", ""),
+			AppToken:   getEnv("SLACK_APP_TOKEN", ""),
+		},
+		StorageConfig: StorageConfig{
+			Enabled:                getEnvBool("STORAGE_ENABLED", false),
+			Dir:                    getEnv("STORAGE_DIR", "./storage"),
+			BaseURL:                getEnv("STORAGE_BASE_URL", ""),
+			MinFreeBytes:           getEnvInt64("STORAGE_MIN_FREE_BYTES", 0),
+			TTL:                    getEnvDuration("STORAGE_TTL", 0),
+			JanitorInterval:        getEnvDuration("STORAGE_JANITOR_INTERVAL", time.Hour),
+			UploadProcessorEnabled: getEnvBool("STORAGE_UPLOAD_PROCESSOR_ENABLED", false),
+
+			Backend:      getEnv("STORAGE_BACKEND", "local"),
+			S3Endpoint:   getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3Region:     getEnv("STORAGE_S3_REGION", ""),
+			S3Bucket:     getEnv("STORAGE_S3_BUCKET", ""),
+			S3AccessKey:  getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			S3SecretKey:  getEnv("STORAGE_S3_SECRET_KEY", ""),
+			S3UseSSL:     getEnvBool("STORAGE_S3_USE_SSL", true),
+			S3PublicBase: getEnv("STORAGE_S3_PUBLIC_BASE", ""),
+			S3QuotaBytes: getEnvInt64("STORAGE_S3_QUOTA_BYTES", 0),
+
+			SignedURLsEnabled: getEnvBool("STORAGE_SIGNED_URLS_ENABLED", false),
+			SignSecret:        getEnv("STORAGE_SIGN_SECRET", ""),
+			SignURLTTL:        getEnvDuration("STORAGE_SIGN_URL_TTL", time.Hour),
+		},
+		SlackExportConfig: SlackExportConfig{
+			Enabled:       getEnvBool("SLACK_EXPORT_ENABLED", false),
+			JSONLPath:     getEnv("SLACK_EXPORT_JSONL_PATH", "./slack_export.jsonl"),
+			Sink:          getEnv("SLACK_EXPORT_SINK", "jsonl"),
+			TargetChannel: getEnv("SLACK_EXPORT_TARGET_CHANNEL", ""),
+		},
+	}
+}
+
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -31,16 +31,17 @@ func (container Main) Init() Main {
 		},
 	}
 
-	slackClient := client.SlackClient{
-		Client: &http.Client{
-			Timeout:   time.Duration(5) * time.Second,
-			Transport: netTransport,
-		},
-		BaseURL:    container.Config.SlackConfig.BaseURL,
-		OAuthToken: container.Config.SlackConfig.OAuthToken,
+	httpClient := &http.Client{
+		Timeout:   time.Duration(5) * time.Second,
+		Transport: netTransport,
 	}
 
-	container.SlackClient = slackClient
+	container.SlackClient = client.New(
+		httpClient,
+		container.Config.SlackConfig.BaseURL,
+		container.Config.SlackConfig.OAuthToken,
+		container.Config.SlackConfig.AppToken,
+	)
 
 	return container
 }
\ No newline at end of file
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+//SlackImportChannel is a channel entry decoded from a Slack export's channels.json
+type SlackImportChannel struct {
+	ID   string
+	Name string
+}
+
+//SlackImportUser is a user entry decoded from a Slack export's users.json
+type SlackImportUser struct {
+	ID       string
+	Name     string
+	RealName string
+}
+
+//SlackImportFile is a file attachment referenced by a SlackImportPost
+type SlackImportFile struct {
+	ID                 string
+	Name               string
+	URLPrivateDownload string
+}
+
+//SlackImportPost is a single message decoded from a Slack export's per-channel YYYY-MM-DD.json file
+type SlackImportPost struct {
+	Channel   string
+	User      string
+	Text      string
+	Timestamp time.Time
+	Files     []SlackImportFile
+}
@@ -0,0 +1,50 @@
+//Package fileproc provides a pluggable registry of file processors. Each
+//processor owns one category of incoming Slack file (e.g. zipped email
+//templates, generic uploads) so new file types can be supported by
+//registering a processor instead of editing a hard-coded type switch
+package fileproc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+//FileProcessor handles one category of incoming Slack file
+type FileProcessor interface {
+	//Name identifies the processor in logs and config
+	Name() string
+	//Supports reports whether this processor can handle the given file
+	Supports(file dto.File) bool
+	//Timeout bounds how long Process is allowed to run for a single file
+	Timeout() time.Duration
+	//Process runs the processor against file. The caller is responsible for
+	//reporting a returned error back to channel
+	Process(ctx context.Context, channel string, file dto.File) (dto.File, error)
+}
+
+var registry []FileProcessor
+
+//Register adds a processor to the registry. Processors are matched against
+//an incoming file in registration order, first match wins
+func Register(processor FileProcessor) {
+	registry = append(registry, processor)
+}
+
+//Registered returns the currently registered processors
+func Registered() []FileProcessor {
+	return registry
+}
+
+//Find returns the first registered processor which supports file
+func Find(file dto.File) (FileProcessor, error) {
+	for _, processor := range registry {
+		if processor.Supports(file) {
+			return processor, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no file processor registered for file type %q", file.Filetype)
+}
@@ -0,0 +1,57 @@
+package fileproc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+//stubProcessor is a minimal FileProcessor for exercising the registry
+type stubProcessor struct {
+	name     string
+	supports func(dto.File) bool
+}
+
+func (p stubProcessor) Name() string                    { return p.name }
+func (p stubProcessor) Timeout() time.Duration           { return time.Second }
+func (p stubProcessor) Supports(file dto.File) bool      { return p.supports(file) }
+func (p stubProcessor) Process(ctx context.Context, channel string, file dto.File) (dto.File, error) {
+	return file, nil
+}
+
+func withRegistry(t *testing.T, processors ...FileProcessor) {
+	original := registry
+	registry = nil
+	t.Cleanup(func() { registry = original })
+
+	for _, processor := range processors {
+		Register(processor)
+	}
+}
+
+func TestFindReturnsFirstMatchInRegistrationOrder(t *testing.T) {
+	matchesAnything := func(dto.File) bool { return true }
+
+	first := stubProcessor{name: "first", supports: matchesAnything}
+	second := stubProcessor{name: "second", supports: matchesAnything}
+	withRegistry(t, first, second)
+
+	found, err := Find(dto.File{Filetype: "zip"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if found.Name() != first.Name() {
+		t.Fatalf("expected the first registered matching processor %q, got %q", first.Name(), found.Name())
+	}
+}
+
+func TestFindReturnsErrorWhenNoneSupportFile(t *testing.T) {
+	withRegistry(t, stubProcessor{name: "never", supports: func(dto.File) bool { return false }})
+
+	if _, err := Find(dto.File{Filetype: "zip"}); err == nil {
+		t.Fatalf("expected an error when no processor supports the file")
+	}
+}
@@ -0,0 +1,91 @@
+package fileproc
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/slackimport"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+const (
+	//SlackExportProcessorName is the registered name of SlackExportProcessor
+	SlackExportProcessorName = "slack-export"
+
+	slackExportTimeout = 5 * time.Minute
+)
+
+//SlackExportProcessor replays a Slack workspace export archive into the
+//configured slackimport.HistoryStore when it spots one of those archives.
+//Attachments may be nil, in which case the export's file attachments are
+//left unfetched rather than downloaded and discarded
+type SlackExportProcessor struct {
+	Store       slackimport.HistoryStore
+	Attachments storage.Backend
+}
+
+//NewSlackExportProcessor creates a SlackExportProcessor which replays into
+//store, persisting file attachments to attachments
+func NewSlackExportProcessor(store slackimport.HistoryStore, attachments storage.Backend) SlackExportProcessor {
+	return SlackExportProcessor{Store: store, Attachments: attachments}
+}
+
+//Name implements FileProcessor
+func (SlackExportProcessor) Name() string {
+	return SlackExportProcessorName
+}
+
+//Timeout implements FileProcessor
+func (SlackExportProcessor) Timeout() time.Duration {
+	return slackExportTimeout
+}
+
+//Supports implements FileProcessor. Slack export archives are zips too, so
+//this relies on the filename convention Slack itself uses ("Slack export
+//... - name.zip"); ThemerProcessor.Supports excludes the same names, so the
+//two don't race on registration order
+func (SlackExportProcessor) Supports(file dto.File) bool {
+	return file.Filetype == zipFileType && strings.Contains(strings.ToLower(file.Name), "export")
+}
+
+//Process implements FileProcessor
+func (p SlackExportProcessor) Process(ctx context.Context, channel string, file dto.File) (dto.File, error) {
+	log.Logger().Debug().Str("url", file.URLPrivate).Msg("Start Slack export import")
+
+	var body bytes.Buffer
+	if err := container.C.SlackClient.DownloadFile(ctx, file.URLPrivate, &body); err != nil {
+		return file, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+	if err != nil {
+		return file, err
+	}
+
+	summary, err := slackimport.NewImporter(p.Store, container.C.SlackClient, p.Attachments).Import(reader)
+	if err != nil {
+		return file, err
+	}
+
+	if flusher, ok := p.Store.(slackimport.Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			return file, err
+		}
+	}
+
+	if _, _, err := container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+		Channel: channel,
+		Text:    fmt.Sprintf("Imported %d channels, %d users and %d posts from the export.", summary.Channels, summary.Users, summary.Posts),
+	}); err != nil {
+		return file, err
+	}
+
+	return file, nil
+}
@@ -0,0 +1,173 @@
+package fileproc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/helper"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+const (
+	//ThemerProcessorName is the registered name of ThemerProcessor
+	ThemerProcessorName = "themer"
+
+	zipFileType           = "zip"
+	defaultResultFilename = "result.zip"
+	themerTimeout         = 2 * time.Minute
+)
+
+//ThemerProcessor unzips an uploaded theme archive, runs it through
+//scripts/themer/themer.phar and ships the compiled result back to the
+//channel: through Backend when one is configured, falling back to
+//SlackClient.AttachFileTo otherwise
+type ThemerProcessor struct {
+	Backend      storage.Backend
+	MinFreeBytes int64
+}
+
+//NewThemerProcessor creates a ThemerProcessor. backend may be nil, in which
+//case the result zip is attached directly via SlackClient.AttachFileTo
+func NewThemerProcessor(backend storage.Backend, minFreeBytes int64) ThemerProcessor {
+	return ThemerProcessor{Backend: backend, MinFreeBytes: minFreeBytes}
+}
+
+//Name implements FileProcessor
+func (ThemerProcessor) Name() string {
+	return ThemerProcessorName
+}
+
+//Timeout implements FileProcessor
+func (ThemerProcessor) Timeout() time.Duration {
+	return themerTimeout
+}
+
+//Supports implements FileProcessor. Slack export archives are zips too, so
+//this excludes the filename convention SlackExportProcessor claims - it's
+//registration-order independent this way, rather than relying on
+//SlackExportProcessor always being registered first
+func (ThemerProcessor) Supports(file dto.File) bool {
+	return file.Filetype == zipFileType && !strings.Contains(strings.ToLower(file.Name), "export")
+}
+
+//Process implements FileProcessor
+func (p ThemerProcessor) Process(ctx context.Context, channel string, file dto.File) (dto.File, error) {
+	log.Logger().Debug().
+		Str("url", file.URLPrivate).
+		Msg("Start processing file")
+
+	//First we need to download the file
+	tmpFile, err := downloadFile(ctx, file.URLPrivate)
+	if err != nil {
+		return file, err
+	}
+
+	//Now we need to unzip the file and save the destination folder path
+	var (
+		src         = os.TempDir() + file.ID
+		pathToFiles = src + "/downloaded_template"
+	)
+	_, err = helper.Unzip(tmpFile.Name(), pathToFiles)
+	if err != nil {
+		return file, err
+	}
+
+	log.Logger().Debug().Str("template_dir", pathToFiles).Msg("Template dir generated")
+
+	//We run the command which compiles the template.
+	//This will create in src 2 directories: one is for template html preview and second one for template
+	cmd := exec.CommandContext(ctx, "./scripts/themer/themer.phar", fmt.Sprintf("--path=%s", pathToFiles))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		log.Logger().AddError(err).
+			Interface("file", file).
+			Msg("Failed generate template")
+		return file, err
+	}
+
+	//Now we need to remove the downloaded dir and zip the contains of src directory
+	if err := deleteSrc(pathToFiles); err != nil {
+		return file, err
+	}
+
+	resultFilePath := src + fmt.Sprintf("/%s", defaultResultFilename)
+	if err := helper.Zip(src, resultFilePath); err != nil {
+		return file, err
+	}
+
+	log.Logger().Debug().Str("result_zip_path", src+"/result.zip").Msg("Zip file created")
+
+	if err := p.deliver(ctx, channel, file.ID+"_"+defaultResultFilename, resultFilePath); err != nil {
+		return file, err
+	}
+
+	if err := deleteSrc(src); err != nil {
+		return file, err
+	}
+
+	return file, nil
+}
+
+//deliver ships the compiled result to channel: through Backend when one is
+//configured, falling back to SlackClient.AttachFileTo otherwise
+func (p ThemerProcessor) deliver(ctx context.Context, channel string, objectName string, resultFilePath string) error {
+	if p.Backend == nil {
+		_, _, err := container.C.SlackClient.AttachFileTo(channel, resultFilePath, defaultResultFilename)
+		return err
+	}
+
+	if err := storage.CheckCapacity(ctx, p.Backend, p.MinFreeBytes); err != nil {
+		if _, _, sendErr := container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+			Channel: channel,
+			Text:    "Sorry, there isn't enough free space on the storage backend right now. Try again later.",
+		}); sendErr != nil {
+			log.Logger().AddError(sendErr).Msg("Failed to report capacity error to channel")
+		}
+		return err
+	}
+
+	resultFile, err := os.Open(resultFilePath)
+	if err != nil {
+		return err
+	}
+	defer resultFile.Close()
+
+	url, err := p.Backend.Put(ctx, objectName, resultFile)
+	if err != nil {
+		log.Logger().AddError(err).Str("result_zip_path", resultFilePath).Msg("Failed to upload themer result to storage backend")
+		return err
+	}
+
+	_, _, err = container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+		Channel: channel,
+		Text:    fmt.Sprintf("Here's your compiled theme: %s", url),
+	})
+	return err
+}
+
+func downloadFile(ctx context.Context, url string) (*os.File, error) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "devbot-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer tmpFile.Close()
+
+	if err := container.C.SlackClient.DownloadFile(ctx, url, tmpFile); err != nil {
+		return nil, err
+	}
+
+	return tmpFile, nil
+}
+
+func deleteSrc(src string) error {
+	return os.RemoveAll(src)
+}
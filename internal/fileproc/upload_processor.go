@@ -0,0 +1,86 @@
+package fileproc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+const (
+	//UploadProcessorName is the registered name of UploadProcessor
+	UploadProcessorName = "upload"
+
+	uploadTimeout = 30 * time.Second
+)
+
+//UploadProcessor is a generic processor: it downloads any non-zip file Slack
+//handed over, pushes it through Backend and replies with the resulting URL.
+//It's meant for operators who just want a generic "store this somewhere and
+//give me a link" handler rather than a bespoke pipeline like ThemerProcessor
+type UploadProcessor struct {
+	Backend      storage.Backend
+	MinFreeBytes int64
+}
+
+//NewUploadProcessor creates an UploadProcessor backed by backend. minFreeBytes
+//is the free-space threshold below which uploads are rejected; 0 disables the guard
+func NewUploadProcessor(backend storage.Backend, minFreeBytes int64) UploadProcessor {
+	return UploadProcessor{Backend: backend, MinFreeBytes: minFreeBytes}
+}
+
+//Name implements FileProcessor
+func (UploadProcessor) Name() string {
+	return UploadProcessorName
+}
+
+//Timeout implements FileProcessor
+func (UploadProcessor) Timeout() time.Duration {
+	return uploadTimeout
+}
+
+//Supports implements FileProcessor. It claims every file that isn't a zip;
+//zips are never taken here, even when no other registered processor claims them
+func (UploadProcessor) Supports(file dto.File) bool {
+	return file.Filetype != zipFileType
+}
+
+//Process implements FileProcessor
+func (p UploadProcessor) Process(ctx context.Context, channel string, file dto.File) (dto.File, error) {
+	log.Logger().Debug().Str("url", file.URLPrivate).Msg("Start generic file upload")
+
+	if err := storage.CheckCapacity(ctx, p.Backend, p.MinFreeBytes); err != nil {
+		if _, _, sendErr := container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+			Channel: channel,
+			Text:    "Sorry, there isn't enough free space on the storage backend right now. Try again later.",
+		}); sendErr != nil {
+			log.Logger().AddError(sendErr).Msg("Failed to report capacity error to channel")
+		}
+		return file, err
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(container.C.SlackClient.DownloadFile(ctx, file.URLPrivate, pipeWriter))
+	}()
+
+	url, err := p.Backend.Put(ctx, file.ID+"_"+file.Name, pipeReader)
+	if err != nil {
+		log.Logger().AddError(err).Interface("file", file).Msg("Failed to upload file to storage backend")
+		return file, err
+	}
+
+	if _, _, err := container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+		Channel: channel,
+		Text:    fmt.Sprintf("Uploaded, here's your link: %s", url),
+	}); err != nil {
+		return file, err
+	}
+
+	return file, nil
+}
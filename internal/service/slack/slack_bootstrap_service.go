@@ -0,0 +1,142 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+
+	goslack "github.com/slack-go/slack"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/fileproc"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/slackimport"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+//storageBackend is the backend Bootstrap wired up, if any. It's what
+//EventHandler's /list and /del slash commands operate against
+var storageBackend storage.Backend
+
+//Bootstrap wires the optional, config-gated pieces of the file pipeline
+//(a storage backend, its janitor) into the running process. It must be
+//called once, after container.C has been initialised, before EventHandler
+//is handed to SlackClient.Run
+func Bootstrap(ctx context.Context) {
+	cfg := container.C.Config.StorageConfig
+	if cfg.Enabled {
+		backend, err := newStorageBackend(cfg)
+		if err != nil {
+			log.Logger().AddError(err).Msg("Failed to construct storage backend, storage-backed processors disabled")
+		} else {
+			storageBackend = backend
+
+			if cfg.TTL > 0 && cfg.JanitorInterval > 0 {
+				janitor := storage.NewJanitor(storageBackend, cfg.TTL, cfg.JanitorInterval)
+				go janitor.Run(ctx)
+			}
+
+			if cfg.UploadProcessorEnabled {
+				fileproc.Register(fileproc.NewUploadProcessor(storageBackend, cfg.MinFreeBytes))
+			}
+		}
+	}
+
+	//ThemerProcessor is always registered, storageBackend may be nil: it
+	//falls back to SlackClient.AttachFileTo when no backend is configured
+	fileproc.Register(fileproc.NewThemerProcessor(storageBackend, cfg.MinFreeBytes))
+
+	//SlackExportConfig is gated by its own toggle, independent of
+	//StorageConfig.Enabled: Importer.downloadAttachments already tolerates a
+	//nil storageBackend by leaving attachments unfetched
+	exportCfg := container.C.Config.SlackExportConfig
+	if exportCfg.Enabled {
+		store, err := newExportStore(exportCfg)
+		if err != nil {
+			log.Logger().AddError(err).Msg("Failed to open Slack export sink, SlackExportProcessor disabled")
+			return
+		}
+
+		fileproc.Register(fileproc.NewSlackExportProcessor(store, storageBackend))
+	}
+}
+
+//newStorageBackend builds the storage.Backend Bootstrap wires up, picked via
+//StorageConfig.Backend. When SignedURLsEnabled is set, the chosen backend is
+//wrapped in storage.HTTPBackend so Put returns a signed, expiring URL instead
+//of the backend's raw one
+func newStorageBackend(cfg config.StorageConfig) (storage.Backend, error) {
+	var backend storage.Backend
+
+	switch cfg.Backend {
+	case "s3":
+		s3Backend, err := storage.NewS3Backend(storage.S3Config{
+			Endpoint:   cfg.S3Endpoint,
+			Region:     cfg.S3Region,
+			Bucket:     cfg.S3Bucket,
+			AccessKey:  cfg.S3AccessKey,
+			SecretKey:  cfg.S3SecretKey,
+			UseSSL:     cfg.S3UseSSL,
+			PublicBase: cfg.S3PublicBase,
+			QuotaBytes: cfg.S3QuotaBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		backend = s3Backend
+	case "local", "":
+		backend = storage.NewLocalBackend(cfg.Dir, cfg.BaseURL)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+
+	if cfg.SignedURLsEnabled {
+		backend = storage.NewHTTPBackend(backend, cfg.BaseURL, cfg.SignSecret, cfg.SignURLTTL)
+	}
+
+	return backend, nil
+}
+
+//newExportStore builds the slackimport.HistoryStore an import is replayed
+//into, picked via SlackExportConfig.Sink
+func newExportStore(cfg config.SlackExportConfig) (slackimport.HistoryStore, error) {
+	switch cfg.Sink {
+	case "repost":
+		return slackimport.NewRepostSink(cfg.TargetChannel), nil
+	case "jsonl", "":
+		return slackimport.NewJSONLSink(cfg.JSONLPath)
+	default:
+		return nil, fmt.Errorf("unknown Slack export sink %q", cfg.Sink)
+	}
+}
+
+//EventHandler builds the client.EventHandler devbot's Socket Mode loop runs:
+//incoming messages and shared files go through processFiles, /list and /del
+//go through the backend Bootstrap configured
+func EventHandler() client.EventHandler {
+	return client.EventHandler{
+		OnMessage: func(message dto.SlackResponseEventMessage) {
+			if _, err := processFiles(&message); err != nil {
+				log.Logger().AddError(err).Msg("Failed to process message files")
+			}
+		},
+		OnFileShared: func(message dto.SlackResponseEventMessage) {
+			if _, err := processFiles(&message); err != nil {
+				log.Logger().AddError(err).Msg("Failed to process shared file")
+			}
+		},
+		OnSlashCommand: func(cmd goslack.SlashCommand) (interface{}, error) {
+			switch cmd.Command {
+			case "/list", "/del":
+				if storageBackend == nil {
+					return nil, fmt.Errorf("no storage backend configured")
+				}
+				return HandleStorageCommand(context.Background(), storageBackend, cmd.Command, cmd.Text)
+			default:
+				return nil, nil
+			}
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+//HandleStorageCommand answers the /list and /del slash commands against backend.
+//It's meant to be wired up as client.EventHandler.OnSlashCommand once a storage
+//backend has been configured
+func HandleStorageCommand(ctx context.Context, backend storage.Backend, command string, text string) (string, error) {
+	switch command {
+	case "/list":
+		return listObjects(ctx, backend)
+	case "/del":
+		return deleteObject(ctx, backend, strings.TrimSpace(text))
+	default:
+		return "", fmt.Errorf("unsupported storage command: %s", command)
+	}
+}
+
+func listObjects(ctx context.Context, backend storage.Backend) (string, error) {
+	objects, err := backend.List(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if len(objects) == 0 {
+		return "No files stored yet.", nil
+	}
+
+	var lines []string
+	for _, object := range objects {
+		lines = append(lines, fmt.Sprintf("%s (%d bytes, uploaded %s)", object.Name, object.Size, object.LastModified.Format("2006-01-02 15:04")))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func deleteObject(ctx context.Context, backend storage.Backend, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("usage: /del <name>")
+	}
+
+	name, err := storage.SanitizeName(name)
+	if err != nil {
+		return "", fmt.Errorf("usage: /del <name>")
+	}
+
+	if err := backend.Delete(ctx, name); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Deleted %s", name), nil
+}
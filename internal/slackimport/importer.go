@@ -0,0 +1,240 @@
+//Package slackimport replays a Slack workspace export (the .zip you get from
+//"Export data") into a configurable sink: a local JSONL file, a database
+//behind HistoryStore, or summarized threads re-posted to a live channel
+package slackimport
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+//HistoryStore persists the channels, users and posts decoded from an export
+type HistoryStore interface {
+	SaveChannel(dto.SlackImportChannel) error
+	SaveUser(dto.SlackImportUser) error
+	SavePost(dto.SlackImportPost) error
+}
+
+//Summary reports how much of an export was imported
+type Summary struct {
+	Channels int
+	Users    int
+	Posts    int
+}
+
+//Downloader fetches the file attachments a Slack export only references by
+//URL. url is an absolute https://files.slack.com/... link, so this must not
+//be prefixed with an API base URL the way SlackClient.Request would
+type Downloader interface {
+	DownloadFile(ctx context.Context, url string, w io.Writer) error
+}
+
+//Flusher is implemented by a HistoryStore which buffers its writes (e.g.
+//RepostSink batching posts into summarized messages) and needs a final
+//nudge once an import finishes
+type Flusher interface {
+	Flush() error
+}
+
+//Importer replays a Slack export archive into a HistoryStore, downloading
+//any referenced file attachments through Downloader and persisting them to
+//Attachments along the way
+type Importer struct {
+	Store       HistoryStore
+	Downloader  Downloader
+	Attachments storage.Backend
+}
+
+//NewImporter creates an Importer which replays into store, downloading
+//attachments through downloader and persisting them to attachments. attachments
+//may be nil, in which case attachments are left unfetched rather than
+//downloaded and discarded
+func NewImporter(store HistoryStore, downloader Downloader, attachments storage.Backend) Importer {
+	return Importer{Store: store, Downloader: downloader, Attachments: attachments}
+}
+
+//Import walks every entry of the export archive and feeds it into Store
+func (importer Importer) Import(reader *zip.Reader) (Summary, error) {
+	var summary Summary
+
+	for _, file := range reader.File {
+		switch {
+		case file.Name == "channels.json":
+			channels, err := decodeChannels(file)
+			if err != nil {
+				return summary, err
+			}
+
+			for _, channel := range channels {
+				if err := importer.Store.SaveChannel(channel); err != nil {
+					return summary, err
+				}
+				summary.Channels++
+			}
+		case file.Name == "users.json":
+			users, err := decodeUsers(file)
+			if err != nil {
+				return summary, err
+			}
+
+			for _, user := range users {
+				if err := importer.Store.SaveUser(user); err != nil {
+					return summary, err
+				}
+				summary.Users++
+			}
+		case isDailyMessageFile(file.Name):
+			channel := filepath.Dir(file.Name)
+			posts, err := decodePosts(file, channel)
+			if err != nil {
+				log.Logger().AddError(err).Str("file", file.Name).Msg("Failed to decode channel history file")
+				continue
+			}
+
+			for _, post := range posts {
+				importer.downloadAttachments(post)
+
+				if err := importer.Store.SavePost(post); err != nil {
+					return summary, err
+				}
+				summary.Posts++
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+//downloadAttachments fetches every file post references, through Downloader,
+//and persists it to Attachments so the import doesn't silently leave
+//dangling URLs behind. Without an Attachments backend configured there's
+//nowhere to put the bytes, so attachments are left unfetched. Failures are
+//logged and skipped rather than failing the whole import
+func (importer Importer) downloadAttachments(post dto.SlackImportPost) {
+	if importer.Downloader == nil || importer.Attachments == nil {
+		return
+	}
+
+	for _, attachedFile := range post.Files {
+		if attachedFile.URLPrivateDownload == "" {
+			continue
+		}
+
+		pipeReader, pipeWriter := io.Pipe()
+		go func(attachedFile dto.SlackImportFile) {
+			pipeWriter.CloseWithError(importer.Downloader.DownloadFile(context.Background(), attachedFile.URLPrivateDownload, pipeWriter))
+		}(attachedFile)
+
+		if _, err := importer.Attachments.Put(context.Background(), attachedFile.ID+"_"+attachedFile.Name, pipeReader); err != nil {
+			log.Logger().AddError(err).Str("file", attachedFile.Name).Msg("Failed to persist Slack export attachment")
+		}
+	}
+}
+
+//isDailyMessageFile reports whether name looks like a per-channel export file, e.g. "general/2021-05-04.json"
+func isDailyMessageFile(name string) bool {
+	base := filepath.Base(name)
+	if !strings.HasSuffix(base, ".json") || filepath.Dir(name) == "." {
+		return false
+	}
+
+	_, err := time.Parse("2006-01-02", strings.TrimSuffix(base, ".json"))
+	return err == nil
+}
+
+func decodeChannels(file *zip.File) ([]dto.SlackImportChannel, error) {
+	var raw []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(file, &raw); err != nil {
+		return nil, err
+	}
+
+	channels := make([]dto.SlackImportChannel, 0, len(raw))
+	for _, entry := range raw {
+		channels = append(channels, dto.SlackImportChannel{ID: entry.ID, Name: entry.Name})
+	}
+
+	return channels, nil
+}
+
+func decodeUsers(file *zip.File) ([]dto.SlackImportUser, error) {
+	var raw []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Profile struct {
+			RealName string `json:"real_name"`
+		} `json:"profile"`
+	}
+	if err := decodeJSON(file, &raw); err != nil {
+		return nil, err
+	}
+
+	users := make([]dto.SlackImportUser, 0, len(raw))
+	for _, entry := range raw {
+		users = append(users, dto.SlackImportUser{ID: entry.ID, Name: entry.Name, RealName: entry.Profile.RealName})
+	}
+
+	return users, nil
+}
+
+func decodePosts(file *zip.File, channel string) ([]dto.SlackImportPost, error) {
+	var raw []struct {
+		User  string `json:"user"`
+		Text  string `json:"text"`
+		Ts    string `json:"ts"`
+		Files []struct {
+			ID                 string `json:"id"`
+			Name               string `json:"name"`
+			URLPrivateDownload string `json:"url_private_download"`
+		} `json:"files"`
+	}
+	if err := decodeJSON(file, &raw); err != nil {
+		return nil, err
+	}
+
+	posts := make([]dto.SlackImportPost, 0, len(raw))
+	for _, entry := range raw {
+		seconds, _ := strconv.ParseFloat(entry.Ts, 64)
+
+		post := dto.SlackImportPost{
+			Channel:   channel,
+			User:      entry.User,
+			Text:      entry.Text,
+			Timestamp: time.Unix(int64(seconds), 0),
+		}
+
+		for _, attachedFile := range entry.Files {
+			post.Files = append(post.Files, dto.SlackImportFile{
+				ID:                 attachedFile.ID,
+				Name:               attachedFile.Name,
+				URLPrivateDownload: attachedFile.URLPrivateDownload,
+			})
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+func decodeJSON(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return json.NewDecoder(reader).Decode(v)
+}
@@ -0,0 +1,209 @@
+package slackimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/storage"
+)
+
+func TestIsDailyMessageFile(t *testing.T) {
+	cases := map[string]bool{
+		"general/2021-05-04.json": true,
+		"random/2021-12-31.json":  true,
+		"channels.json":           false,
+		"users.json":              false,
+		"general/readme.txt":      false,
+		"general/not-a-date.json": false,
+	}
+
+	for name, want := range cases {
+		if got := isDailyMessageFile(name); got != want {
+			t.Errorf("isDailyMessageFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+//fakeHistoryStore records whatever Importer.Import feeds it
+type fakeHistoryStore struct {
+	channels []dto.SlackImportChannel
+	users    []dto.SlackImportUser
+	posts    []dto.SlackImportPost
+}
+
+func (store *fakeHistoryStore) SaveChannel(channel dto.SlackImportChannel) error {
+	store.channels = append(store.channels, channel)
+	return nil
+}
+
+func (store *fakeHistoryStore) SaveUser(user dto.SlackImportUser) error {
+	store.users = append(store.users, user)
+	return nil
+}
+
+func (store *fakeHistoryStore) SavePost(post dto.SlackImportPost) error {
+	store.posts = append(store.posts, post)
+	return nil
+}
+
+//fakeDownloader records every url it was asked to fetch, optionally failing on some of them
+type fakeDownloader struct {
+	fetched []string
+	failFor map[string]bool
+}
+
+func (downloader *fakeDownloader) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	downloader.fetched = append(downloader.fetched, url)
+	if downloader.failFor[url] {
+		return errors.New("download failed")
+	}
+	return nil
+}
+
+//fakeBackend records every name it was asked to store
+type fakeBackend struct {
+	stored []string
+}
+
+func (backend *fakeBackend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return "", err
+	}
+	backend.stored = append(backend.stored, name)
+	return "https://storage.example.com/" + name, nil
+}
+
+func (backend *fakeBackend) List(ctx context.Context) ([]storage.Object, error) { return nil, nil }
+func (backend *fakeBackend) Delete(ctx context.Context, name string) error      { return nil }
+func (backend *fakeBackend) Stat(ctx context.Context) (storage.Stats, error)    { return storage.Stats{}, nil }
+
+func buildExportZip(t *testing.T) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	files := map[string]string{
+		"channels.json": `[{"id":"C1","name":"general"}]`,
+		"users.json":    `[{"id":"U1","name":"alice","profile":{"real_name":"Alice Example"}}]`,
+		"general/2021-05-04.json": `[{"user":"U1","text":"hello","ts":"1620086400.000000","files":[{"id":"F1","name":"cat.png","url_private_download":"https://files.slack.com/cat.png"}]}]`,
+	}
+
+	for name, content := range files {
+		part, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %q in test zip: %v", name, err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %q in test zip: %v", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close test zip writer: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to reopen test zip: %v", err)
+	}
+
+	return reader
+}
+
+func TestImporterImportReplaysChannelsUsersAndPosts(t *testing.T) {
+	store := &fakeHistoryStore{}
+	downloader := &fakeDownloader{}
+	backend := &fakeBackend{}
+	importer := NewImporter(store, downloader, backend)
+
+	summary, err := importer.Import(buildExportZip(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Channels != 1 || summary.Users != 1 || summary.Posts != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	if len(store.channels) != 1 || store.channels[0].ID != "C1" || store.channels[0].Name != "general" {
+		t.Fatalf("unexpected channels: %+v", store.channels)
+	}
+
+	if len(store.users) != 1 || store.users[0].ID != "U1" || store.users[0].RealName != "Alice Example" {
+		t.Fatalf("unexpected users: %+v", store.users)
+	}
+
+	if len(store.posts) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(store.posts))
+	}
+
+	post := store.posts[0]
+	if post.Channel != "general" || post.User != "U1" || post.Text != "hello" {
+		t.Fatalf("unexpected post: %+v", post)
+	}
+
+	if len(downloader.fetched) != 1 || downloader.fetched[0] != "https://files.slack.com/cat.png" {
+		t.Fatalf("expected the post's attachment to be downloaded, got %v", downloader.fetched)
+	}
+
+	if len(backend.stored) != 1 || backend.stored[0] != "F1_cat.png" {
+		t.Fatalf("expected the post's attachment to be persisted to the storage backend, got %v", backend.stored)
+	}
+}
+
+func TestImporterDownloadAttachmentsSkipsFailuresAndEmptyURLs(t *testing.T) {
+	downloader := &fakeDownloader{failFor: map[string]bool{"https://files.slack.com/bad.png": true}}
+	importer := NewImporter(&fakeHistoryStore{}, downloader, &fakeBackend{})
+
+	post := dto.SlackImportPost{
+		Files: []dto.SlackImportFile{
+			{Name: "bad.png", URLPrivateDownload: "https://files.slack.com/bad.png"},
+			{Name: "no-url.png", URLPrivateDownload: ""},
+			{Name: "good.png", URLPrivateDownload: "https://files.slack.com/good.png"},
+		},
+	}
+
+	//Must not panic or stop partway through on a failed download
+	importer.downloadAttachments(post)
+
+	want := []string{"https://files.slack.com/bad.png", "https://files.slack.com/good.png"}
+	if len(downloader.fetched) != len(want) {
+		t.Fatalf("expected fetched=%v, got %v", want, downloader.fetched)
+	}
+	for i, url := range want {
+		if downloader.fetched[i] != url {
+			t.Fatalf("expected fetched=%v, got %v", want, downloader.fetched)
+		}
+	}
+}
+
+func TestImporterDownloadAttachmentsNoopWithoutDownloader(t *testing.T) {
+	importer := NewImporter(&fakeHistoryStore{}, nil, &fakeBackend{})
+
+	//Must not panic when no Downloader was configured
+	importer.downloadAttachments(dto.SlackImportPost{
+		Files: []dto.SlackImportFile{{Name: "cat.png", URLPrivateDownload: "https://files.slack.com/cat.png"}},
+	})
+}
+
+func TestImporterDownloadAttachmentsNoopWithoutBackend(t *testing.T) {
+	downloader := &fakeDownloader{}
+	importer := NewImporter(&fakeHistoryStore{}, downloader, nil)
+
+	//Without a storage backend there's nowhere to put the bytes, so the
+	//attachment must be left unfetched rather than downloaded and discarded
+	importer.downloadAttachments(dto.SlackImportPost{
+		Files: []dto.SlackImportFile{{Name: "cat.png", URLPrivateDownload: "https://files.slack.com/cat.png"}},
+	})
+
+	if len(downloader.fetched) != 0 {
+		t.Fatalf("expected no download without a storage backend, got %v", downloader.fetched)
+	}
+}
@@ -0,0 +1,69 @@
+package slackimport
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+//JSONLSink appends every decoded channel/user/post as its own JSON line to Path
+type JSONLSink struct {
+	Path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+//NewJSONLSink creates a JSONLSink writing to path, creating it if necessary
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONLSink{Path: path, file: file}, nil
+}
+
+//SaveChannel implements HistoryStore
+func (sink *JSONLSink) SaveChannel(channel dto.SlackImportChannel) error {
+	return sink.writeLine(struct {
+		Type string `json:"type"`
+		dto.SlackImportChannel
+	}{Type: "channel", SlackImportChannel: channel})
+}
+
+//SaveUser implements HistoryStore
+func (sink *JSONLSink) SaveUser(user dto.SlackImportUser) error {
+	return sink.writeLine(struct {
+		Type string `json:"type"`
+		dto.SlackImportUser
+	}{Type: "user", SlackImportUser: user})
+}
+
+//SavePost implements HistoryStore
+func (sink *JSONLSink) SavePost(post dto.SlackImportPost) error {
+	return sink.writeLine(struct {
+		Type string `json:"type"`
+		dto.SlackImportPost
+	}{Type: "post", SlackImportPost: post})
+}
+
+//Close closes the underlying file
+func (sink *JSONLSink) Close() error {
+	return sink.file.Close()
+}
+
+func (sink *JSONLSink) writeLine(v interface{}) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = sink.file.Write(append(encoded, '\n'))
+	return err
+}
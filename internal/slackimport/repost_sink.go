@@ -0,0 +1,120 @@
+package slackimport
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+//defaultBatchSize is how many posts RepostSink folds into a single summarized message
+const defaultBatchSize = 20
+
+//RepostSink re-posts imported history as summarized threads into TargetChannel,
+//useful for migrating an archive into a channel devbot is already a member of.
+//Posts are batched by channel so a large export doesn't spam TargetChannel
+//or hammer Slack's rate limiter one message per historical post
+type RepostSink struct {
+	TargetChannel string
+	BatchSize     int
+
+	mu       sync.Mutex
+	buffered map[string][]dto.SlackImportPost
+}
+
+//NewRepostSink creates a RepostSink which posts summaries into targetChannel
+func NewRepostSink(targetChannel string) *RepostSink {
+	return &RepostSink{
+		TargetChannel: targetChannel,
+		BatchSize:     defaultBatchSize,
+		buffered:      map[string][]dto.SlackImportPost{},
+	}
+}
+
+//SaveChannel implements HistoryStore. Channels don't need any action of their
+//own: Importer.Import already sets SlackImportPost.Channel to the channel
+//name (Slack names export folders after the channel, not its ID), so
+//SavePost/flush reference channels by name directly with no lookup needed
+func (sink *RepostSink) SaveChannel(dto.SlackImportChannel) error {
+	return nil
+}
+
+//SaveUser implements HistoryStore. Users don't need any action of their own, they're only referenced from posts
+func (sink *RepostSink) SaveUser(dto.SlackImportUser) error {
+	return nil
+}
+
+//SavePost implements HistoryStore. Posts are buffered per channel and only
+//flushed as a single summarized message once BatchSize posts pile up.
+//Locked, since devbot dispatches OnMessage/OnFileShared (and so
+//SlackExportProcessor.Process) on their own goroutine, and a single
+//RepostSink instance can be shared across concurrent imports
+func (sink *RepostSink) SavePost(post dto.SlackImportPost) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	sink.buffered[post.Channel] = append(sink.buffered[post.Channel], post)
+
+	batchSize := sink.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	if len(sink.buffered[post.Channel]) >= batchSize {
+		return sink.flush(post.Channel)
+	}
+
+	return nil
+}
+
+//Flush implements Flusher, posting a summary for every channel with buffered
+//posts left over once an import finishes
+func (sink *RepostSink) Flush() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	for channel := range sink.buffered {
+		if err := sink.flush(channel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//flush assumes sink.mu is already held by the caller
+func (sink *RepostSink) flush(channel string) error {
+	posts := sink.buffered[channel]
+	if len(posts) == 0 {
+		return nil
+	}
+	delete(sink.buffered, channel)
+
+	channelName := channel
+
+	attachments := 0
+	lines := make([]string, 0, len(posts))
+	for _, post := range posts {
+		line := fmt.Sprintf("<@%s>: %s", post.User, post.Text)
+		if len(post.Files) > 0 {
+			attachments += len(post.Files)
+			line += fmt.Sprintf(" (%d attachment(s))", len(post.Files))
+		}
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf("[imported #%s] %d messages", channelName, len(posts))
+	if attachments > 0 {
+		text += fmt.Sprintf(", %d attachment(s)", attachments)
+	}
+	text += ":\n" + strings.Join(lines, "\n")
+
+	_, _, err := container.C.SlackClient.SendMessage(dto.SlackRequestChatPostMessage{
+		Channel: sink.TargetChannel,
+		Text:    text,
+	})
+
+	return err
+}
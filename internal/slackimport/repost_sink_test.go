@@ -0,0 +1,143 @@
+package slackimport
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+//fakeSlackClient implements client.SlackClientInterface, recording SendMessage
+//calls so RepostSink's batching can be asserted against. Every other method is
+//unused by RepostSink and just returns zero values
+type fakeSlackClient struct {
+	sent []dto.SlackRequestChatPostMessage
+}
+
+func (c *fakeSlackClient) Request(string, string, []byte) ([]byte, int, error) { return nil, 0, nil }
+func (c *fakeSlackClient) Post(string, []byte) ([]byte, int, error)            { return nil, 0, nil }
+func (c *fakeSlackClient) Get(string) ([]byte, int, error)                    { return nil, 0, nil }
+func (c *fakeSlackClient) Put(string, []byte) ([]byte, int, error)            { return nil, 0, nil }
+
+
+func (c *fakeSlackClient) RequestContext(ctx context.Context, method string, endpoint string, body []byte) ([]byte, int, error) {
+	return nil, 0, nil
+}
+func (c *fakeSlackClient) PostContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	return nil, 0, nil
+}
+func (c *fakeSlackClient) GetContext(ctx context.Context, endpoint string) ([]byte, int, error) {
+	return nil, 0, nil
+}
+func (c *fakeSlackClient) PutContext(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	return nil, 0, nil
+}
+
+func (c *fakeSlackClient) GetConversationsList() (dto.SlackResponseConversationsList, int, error) {
+	return dto.SlackResponseConversationsList{}, 0, nil
+}
+func (c *fakeSlackClient) GetConversationsListContext(ctx context.Context) (dto.SlackResponseConversationsList, int, error) {
+	return dto.SlackResponseConversationsList{}, 0, nil
+}
+func (c *fakeSlackClient) GetUsersList() (dto.SlackResponseUsersList, int, error) {
+	return dto.SlackResponseUsersList{}, 0, nil
+}
+func (c *fakeSlackClient) GetUsersListContext(ctx context.Context) (dto.SlackResponseUsersList, int, error) {
+	return dto.SlackResponseUsersList{}, 0, nil
+}
+
+func (c *fakeSlackClient) SendMessage(message dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error) {
+	c.sent = append(c.sent, message)
+	return dto.SlackResponseChatPostMessage{}, 0, nil
+}
+func (c *fakeSlackClient) SendMessageContext(ctx context.Context, message dto.SlackRequestChatPostMessage) (dto.SlackResponseChatPostMessage, int, error) {
+	return c.SendMessage(message)
+}
+func (c *fakeSlackClient) AttachFileTo(channel string, filePath string, filename string) (dto.SlackResponseChatPostMessage, int, error) {
+	return dto.SlackResponseChatPostMessage{}, 0, nil
+}
+
+func (c *fakeSlackClient) UploadFile(ctx context.Context, params client.FileUploadParameters, r io.Reader) (dto.File, error) {
+	return dto.File{}, nil
+}
+func (c *fakeSlackClient) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	return nil
+}
+
+func (c *fakeSlackClient) Run(ctx context.Context, handler client.EventHandler) error { return nil }
+
+func withFakeSlackClient(t *testing.T) *fakeSlackClient {
+	t.Helper()
+
+	original := container.C.SlackClient
+	fake := &fakeSlackClient{}
+	container.C.SlackClient = fake
+	t.Cleanup(func() { container.C.SlackClient = original })
+
+	return fake
+}
+
+func TestRepostSinkFlushesOnceBatchSizeIsReached(t *testing.T) {
+	fake := withFakeSlackClient(t)
+
+	sink := NewRepostSink("#imports")
+	sink.BatchSize = 2
+	//Importer.Import always sets SlackImportPost.Channel to the export
+	//folder name, e.g. "general" - never the channel ID
+	sink.SaveChannel(dto.SlackImportChannel{ID: "C1", Name: "general"})
+
+	if err := sink.SavePost(dto.SlackImportPost{Channel: "general", User: "U1", Text: "one"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 0 {
+		t.Fatalf("expected no flush before BatchSize posts, got %d sent", len(fake.sent))
+	}
+
+	if err := sink.SavePost(dto.SlackImportPost{Channel: "general", User: "U1", Text: "two"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 1 {
+		t.Fatalf("expected a single flush once BatchSize posts piled up, got %d sent", len(fake.sent))
+	}
+
+	sent := fake.sent[0]
+	if sent.Channel != "#imports" {
+		t.Fatalf("expected the summary to be posted to TargetChannel, got %q", sent.Channel)
+	}
+	if !strings.Contains(sent.Text, "general") || !strings.Contains(sent.Text, "2 messages") {
+		t.Fatalf("expected summary to reference the channel name and post count, got %q", sent.Text)
+	}
+}
+
+func TestRepostSinkFlushPostsLeftoverBatches(t *testing.T) {
+	fake := withFakeSlackClient(t)
+
+	sink := NewRepostSink("#imports")
+	sink.BatchSize = 10
+
+	if err := sink.SavePost(dto.SlackImportPost{Channel: "general", User: "U1", Text: "one"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 0 {
+		t.Fatalf("expected no flush before Flush is called, got %d sent", len(fake.sent))
+	}
+
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 1 {
+		t.Fatalf("expected Flush to post the leftover batch, got %d sent", len(fake.sent))
+	}
+
+	//A second Flush with nothing buffered must be a no-op
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 1 {
+		t.Fatalf("expected Flush with no buffered posts to send nothing more, got %d sent", len(fake.sent))
+	}
+}
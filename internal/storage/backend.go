@@ -0,0 +1,76 @@
+//Package storage provides a pluggable object-storage abstraction for
+//whatever devbot produces (themed zips, imported Slack archives, ...),
+//together with a disk-quota guard and a janitor for expiring old objects
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//ErrInsufficientCapacity is returned by CheckCapacity when a backend doesn't
+//have enough free space left to safely accept a new upload
+var ErrInsufficientCapacity = errors.New("storage backend is below the configured free space threshold")
+
+//Object describes a single stored object, as returned by Backend.List
+type Object struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+//Stats describes the current usage of a Backend
+type Stats struct {
+	UsedBytes      int64
+	AvailableBytes int64
+}
+
+//Backend is implemented by every storage destination devbot can upload to
+type Backend interface {
+	//Put stores r under name and returns a URL the object can be downloaded from
+	Put(ctx context.Context, name string, r io.Reader) (url string, err error)
+	//List returns every object currently stored
+	List(ctx context.Context) ([]Object, error)
+	//Delete removes the object called name
+	Delete(ctx context.Context, name string) error
+	//Stat reports current usage, used for the free-space guard
+	Stat(ctx context.Context) (Stats, error)
+}
+
+//SanitizeName rejects object names that could escape a backend's storage
+//root (path separators, "..", or an absolute path), so a crafted Slack
+//filename or slash-command argument can't be used for path traversal
+func SanitizeName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("object name must not be empty")
+	}
+
+	if filepath.IsAbs(name) || strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", fmt.Errorf("invalid object name %q", name)
+	}
+
+	return name, nil
+}
+
+//CheckCapacity rejects an upload when backend reports less than minFreeBytes available
+func CheckCapacity(ctx context.Context, backend Backend, minFreeBytes int64) error {
+	if minFreeBytes <= 0 {
+		return nil
+	}
+
+	stats, err := backend.Stat(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stats.AvailableBytes < minFreeBytes {
+		return ErrInsufficientCapacity
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	valid := []string{"report.zip", "theme_2021-05-04.zip", "a.b.c"}
+	for _, name := range valid {
+		if _, err := SanitizeName(name); err != nil {
+			t.Errorf("SanitizeName(%q) returned unexpected error: %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "..", "../etc/passwd", "/etc/passwd", "a/../../b", "a\\b"}
+	for _, name := range invalid {
+		if _, err := SanitizeName(name); err == nil {
+			t.Errorf("SanitizeName(%q) expected an error, got nil", name)
+		}
+	}
+}
+
+//fakeBackend reports fixed Stats, used to exercise CheckCapacity without touching a real backend
+type fakeBackend struct {
+	stats Stats
+}
+
+func (backend fakeBackend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	return "", nil
+}
+func (backend fakeBackend) List(ctx context.Context) ([]Object, error)   { return nil, nil }
+func (backend fakeBackend) Delete(ctx context.Context, name string) error { return nil }
+func (backend fakeBackend) Stat(ctx context.Context) (Stats, error)      { return backend.stats, nil }
+
+func TestCheckCapacity(t *testing.T) {
+	t.Run("disabled guard always passes", func(t *testing.T) {
+		if err := CheckCapacity(context.Background(), fakeBackend{}, 0); err != nil {
+			t.Fatalf("expected nil error when minFreeBytes <= 0, got %v", err)
+		}
+	})
+
+	t.Run("passes when enough free space", func(t *testing.T) {
+		backend := fakeBackend{stats: Stats{AvailableBytes: 1024}}
+		if err := CheckCapacity(context.Background(), backend, 512); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("rejects when below threshold", func(t *testing.T) {
+		backend := fakeBackend{stats: Stats{AvailableBytes: 100}}
+		if err := CheckCapacity(context.Background(), backend, 512); err != ErrInsufficientCapacity {
+			t.Fatalf("expected ErrInsufficientCapacity, got %v", err)
+		}
+	})
+}
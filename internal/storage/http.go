@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+//HTTPBackend wraps another Backend and, instead of handing back that
+//backend's raw URL, returns a signed, expiring download URL of the form
+//BaseURL+"/files/"+name. This package doesn't ship the HTTP server that
+//would serve that route; whatever does must call VerifySignature on the
+//"expires" and "sig" query params before streaming the underlying object back
+type HTTPBackend struct {
+	Backend
+	BaseURL string
+	Secret  string
+	URLTTL  time.Duration
+}
+
+//NewHTTPBackend wraps backend so Put returns signed URLs valid for urlTTL
+func NewHTTPBackend(backend Backend, baseURL string, secret string, urlTTL time.Duration) HTTPBackend {
+	return HTTPBackend{Backend: backend, BaseURL: baseURL, Secret: secret, URLTTL: urlTTL}
+}
+
+//Put implements Backend. The underlying backend still does the actual storing
+func (backend HTTPBackend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	if _, err := backend.Backend.Put(ctx, name, r); err != nil {
+		return "", err
+	}
+
+	return backend.SignedURL(name), nil
+}
+
+//SignedURL returns a download URL for name which is valid until it expires
+func (backend HTTPBackend) SignedURL(name string) string {
+	expires := time.Now().Add(backend.URLTTL).Unix()
+	return fmt.Sprintf("%s/files/%s?expires=%d&sig=%s", backend.BaseURL, name, expires, backend.sign(name, expires))
+}
+
+//VerifySignature reports whether sig is a valid, unexpired signature for name
+func (backend HTTPBackend) VerifySignature(name string, expires int64, sig string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(backend.sign(name, expires)))
+}
+
+func (backend HTTPBackend) sign(name string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(backend.Secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", name, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
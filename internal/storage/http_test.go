@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHTTPBackendVerifySignature(t *testing.T) {
+	backend := NewHTTPBackend(nil, "https://files.example.com", "s3cr3t", time.Hour)
+
+	expires := time.Now().Add(time.Hour).Unix()
+	sig := backend.sign("report.zip", expires)
+
+	if !backend.VerifySignature("report.zip", expires, sig) {
+		t.Fatalf("expected valid signature to verify")
+	}
+
+	if backend.VerifySignature("report.zip", expires, sig+"tampered") {
+		t.Fatalf("expected tampered signature to fail verification")
+	}
+
+	if backend.VerifySignature("other.zip", expires, sig) {
+		t.Fatalf("expected signature for a different name to fail verification")
+	}
+
+	if backend.VerifySignature("report.zip", time.Now().Add(-time.Minute).Unix(), sig) {
+		t.Fatalf("expected expired signature to fail verification")
+	}
+}
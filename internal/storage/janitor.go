@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/sharovik/devbot/internal/log"
+)
+
+//Janitor periodically deletes objects older than TTL from Backend
+type Janitor struct {
+	Backend  Backend
+	TTL      time.Duration
+	Interval time.Duration
+}
+
+//NewJanitor creates a Janitor which sweeps backend every interval, removing
+//objects older than ttl
+func NewJanitor(backend Backend, ttl time.Duration, interval time.Duration) Janitor {
+	return Janitor{Backend: backend, TTL: ttl, Interval: interval}
+}
+
+//Run blocks, sweeping on every tick, until ctx is cancelled
+func (janitor Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(janitor.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			janitor.sweep(ctx)
+		}
+	}
+}
+
+func (janitor Janitor) sweep(ctx context.Context) {
+	objects, err := janitor.Backend.List(ctx)
+	if err != nil {
+		log.Logger().AddError(err).Msg("Janitor failed to list objects")
+		return
+	}
+
+	cutoff := time.Now().Add(-janitor.TTL)
+	for _, object := range objects {
+		if object.LastModified.After(cutoff) {
+			continue
+		}
+
+		if err := janitor.Backend.Delete(ctx, object.Name); err != nil {
+			log.Logger().AddError(err).Str("name", object.Name).Msg("Janitor failed to delete expired object")
+			continue
+		}
+
+		log.Logger().Debug().Str("name", object.Name).Msg("Janitor deleted expired object")
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+//LocalBackend stores objects as plain files under Dir and serves links
+//rooted at BaseURL (e.g. a static file server devbot runs alongside itself)
+type LocalBackend struct {
+	Dir     string
+	BaseURL string
+}
+
+//NewLocalBackend creates a LocalBackend rooted at dir
+func NewLocalBackend(dir string, baseURL string) LocalBackend {
+	return LocalBackend{Dir: dir, BaseURL: baseURL}
+}
+
+//Put implements Backend
+func (backend LocalBackend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	name, err := SanitizeName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(backend.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(backend.Dir, name)
+	file, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", backend.BaseURL, name), nil
+}
+
+//List implements Backend
+func (backend LocalBackend) List(ctx context.Context) ([]Object, error) {
+	entries, err := ioutil.ReadDir(backend.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		objects = append(objects, Object{
+			Name:         entry.Name(),
+			Size:         entry.Size(),
+			LastModified: entry.ModTime(),
+		})
+	}
+
+	return objects, nil
+}
+
+//Delete implements Backend
+func (backend LocalBackend) Delete(ctx context.Context, name string) error {
+	name, err := SanitizeName(name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(backend.Dir, name))
+}
+
+//Stat implements Backend. AvailableBytes comes straight from the filesystem statfs call
+func (backend LocalBackend) Stat(ctx context.Context) (Stats, error) {
+	if err := os.MkdirAll(backend.Dir, 0755); err != nil {
+		return Stats{}, err
+	}
+
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(backend.Dir, &fsStat); err != nil {
+		return Stats{}, err
+	}
+
+	objects, err := backend.List(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var used int64
+	for _, object := range objects {
+		used += object.Size
+	}
+
+	return Stats{
+		UsedBytes:      used,
+		AvailableBytes: int64(fsStat.Bavail) * int64(fsStat.Bsize),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+//S3Config holds the connection details for an S3-compatible object store (AWS S3, MinIO, ...)
+type S3Config struct {
+	Endpoint   string
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	UseSSL     bool
+	PublicBase string
+	QuotaBytes int64
+}
+
+//S3Backend stores objects in an S3-compatible bucket
+type S3Backend struct {
+	client *s3.S3
+	cfg    S3Config
+}
+
+//NewS3Backend creates an S3Backend from cfg
+func NewS3Backend(cfg S3Config) (S3Backend, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(cfg.Endpoint),
+		Region:           aws.String(cfg.Region),
+		Credentials:      credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""),
+		DisableSSL:       aws.Bool(!cfg.UseSSL),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return S3Backend{}, err
+	}
+
+	return S3Backend{client: s3.New(sess), cfg: cfg}, nil
+}
+
+//Put implements Backend
+func (backend S3Backend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := backend.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(backend.cfg.Bucket),
+		Key:    aws.String(name),
+		Body:   bytes.NewReader(body),
+	}); err != nil {
+		return "", err
+	}
+
+	if backend.cfg.PublicBase != "" {
+		return fmt.Sprintf("%s/%s", backend.cfg.PublicBase, name), nil
+	}
+
+	return fmt.Sprintf("%s/%s/%s", backend.cfg.Endpoint, backend.cfg.Bucket, name), nil
+}
+
+//List implements Backend
+func (backend S3Backend) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	err := backend.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(backend.cfg.Bucket),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			objects = append(objects, Object{
+				Name:         aws.StringValue(item.Key),
+				Size:         aws.Int64Value(item.Size),
+				LastModified: aws.TimeValue(item.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+//Delete implements Backend
+func (backend S3Backend) Delete(ctx context.Context, name string) error {
+	_, err := backend.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(backend.cfg.Bucket),
+		Key:    aws.String(name),
+	})
+	return err
+}
+
+//Stat implements Backend. S3-compatible stores don't expose free space, so
+//AvailableBytes is derived from the configured quota minus what's in use
+func (backend S3Backend) Stat(ctx context.Context) (Stats, error) {
+	objects, err := backend.List(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var used int64
+	for _, object := range objects {
+		used += object.Size
+	}
+
+	available := backend.cfg.QuotaBytes - used
+	if backend.cfg.QuotaBytes <= 0 {
+		available = 1<<63 - 1
+	}
+
+	return Stats{UsedBytes: used, AvailableBytes: available}, nil
+}